@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// App ties together the running config, scheduler and metrics so the admin
+// API can inspect and reload them while jobs are executing.
+type App struct {
+	mu sync.Mutex
+
+	ConfigPath string
+	Config     *Config
+	Scheduler  *cron.Cron
+	Metrics    *Metrics
+}
+
+// Builds the admin API routes.
+func (a *App) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.handleHealthz)
+	mux.HandleFunc("/readyz", a.handleReadyz)
+	mux.HandleFunc("/reload", a.handleReload)
+	mux.HandleFunc("/jobs", a.handleJobs)
+	mux.HandleFunc("/jobs/", a.handleJobRun)
+
+	return mux
+}
+
+// Always reports healthy once the process is serving requests.
+func (a *App) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Reports ready once a config is loaded and the scheduler is running.
+func (a *App) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	ready := a.Config != nil && a.Scheduler != nil
+	a.mu.Unlock()
+
+	if !ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Lists all configured jobs and their last-run status.
+func (a *App) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.mu.Lock()
+	jobs := a.Config.CRLJobs
+	a.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.Status())
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// Triggers a single job synchronously by its stable Name: POST
+// /jobs/{name}/run. Responds with the resulting status plus the run's
+// top-level log lines.
+func (a *App) handleJobRun(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] != "run" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job := a.jobByName(parts[0])
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	status, lines := job.RunCaptured()
+	writeJSON(w, http.StatusOK, struct {
+		JobStatus
+		Log []string `json:"log,omitempty"`
+	}{JobStatus: status, Log: lines})
+}
+
+// Finds a configured job by its stable Name.
+func (a *App) jobByName(name string) *CRLJob {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, job := range a.Config.CRLJobs {
+		if job.Name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Re-reads the config file and rebuilds the cron scheduler without restarting: POST /reload.
+func (a *App) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.mu.Lock()
+	cfgPath := a.ConfigPath
+	a.mu.Unlock()
+
+	cfgFile, err := os.Open(cfgPath)
+	if err != nil {
+		log.Error().Str("cfg", cfgPath).Err(err).Msg("reload: config file opening failed")
+		http.Error(w, errors.Wrap(err, "config file opening failed").Error(), http.StatusInternalServerError)
+		return
+	}
+	cfg, err := makeConfig(bufio.NewReader(cfgFile))
+	cfgFile.Close()
+	if err != nil {
+		log.Error().Str("cfg", cfgPath).Err(err).Msg("reload: config file parsing failed")
+		http.Error(w, errors.Wrap(err, "config file parsing failed").Error(), http.StatusInternalServerError)
+		return
+	}
+
+	newSched := buildScheduler(cfg, a.Metrics)
+
+	a.mu.Lock()
+	oldSched := a.Scheduler
+	a.Config = cfg
+	a.Scheduler = newSched
+	a.mu.Unlock()
+
+	oldSched.Stop()
+	newSched.Start()
+
+	log.Info().Str("cfg", cfgPath).Msg("reloaded config and rebuilt scheduler")
+	writeJSON(w, http.StatusOK, struct {
+		Jobs int `json:"jobs"`
+	}{Jobs: len(cfg.CRLJobs)})
+}
+
+// Writes a JSON response body, logging (but not surfacing) encode failures.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("failed to write JSON response")
+	}
+}
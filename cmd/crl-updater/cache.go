@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// cacheMeta records the HTTP caching details of the last successful CRL
+// download, persisted in a sidecar file next to the job's destination so
+// subsequent runs can make a conditional request instead of re-downloading
+// an unchanged CRL.
+type cacheMeta struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Expires      time.Time `json:"expires,omitempty"`
+}
+
+// loadCacheMeta reads a job's sidecar cache file, if present.
+func loadCacheMeta(path string) *cacheMeta {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	meta := &cacheMeta{}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return nil
+	}
+
+	return meta
+}
+
+// saveCacheMeta persists the job's sidecar cache file and remembers the
+// cache expiry, for the "auto" schedule to honor.
+func (j *CRLJob) saveCacheMeta(meta *cacheMeta) {
+	if meta == nil {
+		return
+	}
+
+	j.mu.Lock()
+	j.cacheExpires = meta.Expires
+	j.mu.Unlock()
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.MetaPath), 0755); err != nil {
+		log.Warn().Str("id", j.Name).Str("dest", j.Destination).Err(err).Msg("failed to create CRL cache metadata directory")
+		return
+	}
+	if err := os.WriteFile(j.MetaPath, b, 0644); err != nil {
+		log.Warn().Str("id", j.Name).Str("dest", j.Destination).Err(err).Msg("failed to persist CRL cache metadata")
+	}
+}
+
+// applyConditionalHeaders sets If-None-Match / If-Modified-Since on a
+// request from a previous response's cache metadata, if any.
+func applyConditionalHeaders(req *http.Request, cache *cacheMeta) {
+	if cache == nil {
+		return
+	}
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
+}
+
+// responseCacheMeta builds a cacheMeta from a response's caching headers.
+// A 304 response often omits ETag/Last-Modified, so they're carried over
+// from the previous cache metadata when absent.
+func responseCacheMeta(r *http.Response, prev *cacheMeta) *cacheMeta {
+	meta := &cacheMeta{}
+	if prev != nil {
+		meta.ETag = prev.ETag
+		meta.LastModified = prev.LastModified
+	}
+	if etag := r.Header.Get("ETag"); etag != "" {
+		meta.ETag = etag
+	}
+	if lm := r.Header.Get("Last-Modified"); lm != "" {
+		meta.LastModified = lm
+	}
+	meta.Expires = parseCacheExpiry(r.Header)
+
+	return meta
+}
+
+// parseCacheExpiry derives the time before which the source shouldn't need
+// rechecking, from Cache-Control: max-age or Expires.
+func parseCacheExpiry(h http.Header) time.Time {
+	if cc := h.Get("Cache-Control"); cc != "" {
+		for _, part := range strings.Split(cc, ",") {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, "max-age=") {
+				if secs, err := strconv.Atoi(strings.TrimPrefix(part, "max-age=")); err == nil && secs > 0 {
+					return time.Now().Add(time.Duration(secs) * time.Second)
+				}
+			}
+		}
+	}
+	if exp := h.Get("Expires"); exp != "" {
+		if t, err := http.ParseTime(exp); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
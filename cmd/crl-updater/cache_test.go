@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseCacheExpiry(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		header   http.Header
+		wantZero bool
+		want     time.Duration // approximate offset from now, ignored if wantZero
+	}{
+		{"no headers", http.Header{}, true, 0},
+		{"max-age", http.Header{"Cache-Control": {"max-age=60"}}, false, 60 * time.Second},
+		{"max-age zero", http.Header{"Cache-Control": {"max-age=0"}}, true, 0},
+		{"max-age negative", http.Header{"Cache-Control": {"max-age=-5"}}, true, 0},
+		{"max-age among other directives", http.Header{"Cache-Control": {"no-cache, max-age=30, must-revalidate"}}, false, 30 * time.Second},
+		{"garbage cache-control falls back to expires", http.Header{
+			"Cache-Control": {"nonsense"},
+			"Expires":       {now.Add(time.Hour).UTC().Format(http.TimeFormat)},
+		}, false, time.Hour},
+		{"expires only", http.Header{"Expires": {now.Add(2 * time.Minute).UTC().Format(http.TimeFormat)}}, false, 2 * time.Minute},
+		{"unparseable expires", http.Header{"Expires": {"not-a-date"}}, true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseCacheExpiry(c.header)
+			if c.wantZero {
+				if !got.IsZero() {
+					t.Errorf("parseCacheExpiry() = %v, want zero", got)
+				}
+				return
+			}
+			if got.IsZero() {
+				t.Fatalf("parseCacheExpiry() = zero, want ~%v", c.want)
+			}
+			diff := got.Sub(now) - c.want
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > time.Second {
+				t.Errorf("parseCacheExpiry() = %v, want ~now+%v", got, c.want)
+			}
+		})
+	}
+}
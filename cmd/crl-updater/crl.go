@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// Parses a DER or PEM encoded CRL.
+func parseCRL(raw []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		raw = block.Bytes
+	}
+	return x509.ParseRevocationList(raw)
+}
+
+// Reads back the destination's current content, returning it along with
+// whether it's a parseable CRL.
+func (j *CRLJob) readValidCRL() ([]byte, bool) {
+	r, err := j.dst.Read()
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, false
+	}
+
+	if _, err := parseCRL(raw); err != nil {
+		return nil, false
+	}
+	return raw, true
+}
+
+// Refreshes the CRL-derived metrics and remembers the published CRL's
+// nextUpdate for the "auto" schedule. Called after every run, success or
+// failure, so staleness is detected even if downloads have been failing
+// silently. raw is the already-read destination content when the caller
+// knows it's still current (e.g. a notModified run); otherwise nil, and
+// the destination is read here.
+func (j *CRLJob) refreshCRLMetrics(raw []byte) {
+	if raw == nil {
+		r, err := j.dst.Read()
+		if err != nil {
+			return
+		}
+		defer r.Close()
+
+		var err2 error
+		raw, err2 = ioutil.ReadAll(r)
+		if err2 != nil {
+			log.Warn().Str("id", j.Name).Str("dest", j.Destination).Err(err2).Msg("failed to read published CRL for metrics")
+			return
+		}
+	}
+
+	crl, err := parseCRL(raw)
+	if err != nil {
+		log.Warn().Str("id", j.Name).Str("dest", j.Destination).Err(err).Msg("failed to parse published CRL for metrics")
+		return
+	}
+
+	labels := prometheus.Labels{"job": j.Name, "file": j.Destination}
+	j.Metrics.ThisUpdate.With(labels).Set(float64(crl.ThisUpdate.Unix()))
+	j.Metrics.NextUpdate.With(labels).Set(float64(crl.NextUpdate.Unix()))
+	j.Metrics.RevokedCount.With(labels).Set(float64(len(crl.RevokedCertificates)))
+	j.Metrics.IssuerInfo.With(prometheus.Labels{"job": labels["job"], "file": labels["file"], "cn": crl.Issuer.CommonName}).Set(1)
+
+	expired := 0.0
+	if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+		expired = 1
+		log.Error().Str("id", j.Name).Str("dest", j.Destination).Time("next_update", crl.NextUpdate).Msg("published CRL is past its next update time")
+	}
+	j.Metrics.Expired.With(labels).Set(expired)
+
+	j.mu.Lock()
+	j.nextUpdate = crl.NextUpdate
+	j.mu.Unlock()
+}
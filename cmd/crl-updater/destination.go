@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/google/renameio"
+	"github.com/pkg/errors"
+)
+
+// Destination publishes a downloaded CRL to some target location. Open
+// stages the content, Commit publishes it and Cleanup discards a
+// non-committed (or failed) attempt.
+type Destination interface {
+	// Opens a writer to stage the new CRL content.
+	Open() (io.WriteCloser, error)
+	// Returns the SHA-256 hash of the currently published CRL, if any.
+	CurrentHash() ([]byte, error)
+	// Returns a reader over the currently published content, if any.
+	Read() (io.ReadCloser, error)
+	// Publishes the staged content.
+	Commit() error
+	// Releases any resources held by a non-committed attempt.
+	Cleanup()
+}
+
+// DestConfig is a discriminated union of destination backend parameters.
+type DestConfig struct {
+	// Backend to use: file (default), s3, webdav or http
+	Type string `yaml:"type"`
+
+	// file backend
+	Path  string `yaml:"path"`
+	Mode  uint32 `yaml:"mode"`
+	Owner string `yaml:"owner"`
+	Group string `yaml:"group"`
+
+	// s3 backend
+	Bucket    string `yaml:"bucket"`
+	Key       string `yaml:"key"`
+	Region    string `yaml:"region"`
+	Endpoint  string `yaml:"endpoint"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+
+	// webdav and http backends
+	URL      string            `yaml:"url"`
+	Username string            `yaml:"username"`
+	Password string            `yaml:"password"`
+	Method   string            `yaml:"method"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+// Label returns a short human-readable identifier for this destination, for logs and metrics.
+func (c *DestConfig) Label() string {
+	switch c.Type {
+	case "s3":
+		return fmt.Sprintf("s3://%s/%s", c.Bucket, c.Key)
+	case "webdav", "http":
+		return c.URL
+	default:
+		return c.Path
+	}
+}
+
+// defaultMetaPath returns where a job's HTTP cache metadata sidecar lives
+// when not set explicitly: next to the destination file itself, or under
+// the system temp directory (keyed by label) for remote destinations.
+func (c *DestConfig) defaultMetaPath() string {
+	if c.Type == "" || c.Type == "file" {
+		return c.Path + ".meta.json"
+	}
+
+	sum := sha256.Sum256([]byte(c.Label()))
+	return filepath.Join(os.TempDir(), "crl-updater", hex.EncodeToString(sum[:])+".meta.json")
+}
+
+// Builds a Destination from its configuration.
+func newDestination(cfg *DestConfig) (Destination, error) {
+	switch cfg.Type {
+	case "", "file":
+		return newFileDestination(cfg)
+	case "s3":
+		return newS3Destination(cfg)
+	case "webdav":
+		return newWebDAVDestination(cfg)
+	case "http":
+		return newHTTPDestination(cfg)
+	default:
+		return nil, errors.Errorf("unknown destination type: %s", cfg.Type)
+	}
+}
+
+// bufferWriteCloser lets a Destination stage content in memory (bounded by
+// SizeLimit, like the on-disk temp file) before a single Commit call.
+type bufferWriteCloser struct {
+	*bytes.Buffer
+}
+
+func (bufferWriteCloser) Close() error { return nil }
+
+// fileDestination writes the CRL to a local file, atomically replacing any
+// existing one. It's the original destination behavior, now behind the
+// Destination interface.
+type fileDestination struct {
+	path string
+	mode uint32
+	uid  int
+	gid  int
+
+	pf *renameio.PendingFile
+}
+
+func newFileDestination(cfg *DestConfig) (*fileDestination, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("file destination requires 'path'")
+	}
+
+	d := &fileDestination{path: cfg.Path, mode: cfg.Mode}
+
+	if runtime.GOOS != "windows" {
+		if cfg.Owner != "" {
+			u, err := user.Lookup(cfg.Owner)
+			if err != nil {
+				return nil, errors.Wrap(err, "user lookup failed")
+			}
+			if d.uid, err = strconv.Atoi(u.Uid); err != nil {
+				return nil, errors.Wrap(err, "uid conversion failed")
+			}
+		} else {
+			d.uid = os.Getuid()
+		}
+
+		if cfg.Group != "" {
+			g, err := user.LookupGroup(cfg.Group)
+			if err != nil {
+				return nil, errors.Wrap(err, "group lookup failed")
+			}
+			if d.gid, err = strconv.Atoi(g.Gid); err != nil {
+				return nil, errors.Wrap(err, "gid conversion failed")
+			}
+		} else {
+			d.gid = os.Getgid()
+		}
+
+		if d.mode == 0 {
+			d.mode = DefaultFileMode
+		}
+	}
+
+	return d, nil
+}
+
+func (d *fileDestination) Open() (io.WriteCloser, error) {
+	pf, err := renameio.TempFile(renameio.TempDir(filepath.Dir(d.path)), d.path)
+	if err != nil {
+		return nil, err
+	}
+	d.pf = pf
+
+	return pf, nil
+}
+
+func (d *fileDestination) Read() (io.ReadCloser, error) {
+	return os.Open(d.path)
+}
+
+func (d *fileDestination) CurrentHash() ([]byte, error) {
+	f, err := d.Read()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, bufio.NewReader(f)); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+func (d *fileDestination) Commit() error {
+	if runtime.GOOS != "windows" {
+		if err := os.Chown(d.pf.Name(), d.uid, d.gid); err != nil {
+			return errors.Wrap(err, "temporary file chown failed")
+		}
+		if err := os.Chmod(d.pf.Name(), os.FileMode(d.mode)); err != nil {
+			return errors.Wrap(err, "temporary file chmod failed")
+		}
+	}
+
+	return d.pf.CloseAtomicallyReplace()
+}
+
+func (d *fileDestination) Cleanup() {
+	if d.pf != nil {
+		d.pf.Cleanup()
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// httpPutDestination publishes the CRL to a URL via an HTTP PUT or POST
+// request, with optional Basic Auth and extra headers. It backs both the
+// "http" and "webdav" destination types, since a WebDAV PUT is just an
+// authenticated HTTP PUT.
+type httpPutDestination struct {
+	url      string
+	method   string
+	username string
+	password string
+	headers  map[string]string
+	client   *http.Client
+
+	buf *bytes.Buffer
+}
+
+func newHTTPDestination(cfg *DestConfig) (*httpPutDestination, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("http destination requires 'url'")
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPut
+	}
+
+	return &httpPutDestination{
+		url:      cfg.URL,
+		method:   method,
+		username: cfg.Username,
+		password: cfg.Password,
+		headers:  cfg.Headers,
+		client:   &http.Client{},
+	}, nil
+}
+
+func newWebDAVDestination(cfg *DestConfig) (*httpPutDestination, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webdav destination requires 'url'")
+	}
+
+	return newHTTPDestination(&DestConfig{
+		URL:      cfg.URL,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		Headers:  cfg.Headers,
+		Method:   http.MethodPut,
+	})
+}
+
+func (d *httpPutDestination) Open() (io.WriteCloser, error) {
+	d.buf = &bytes.Buffer{}
+	return bufferWriteCloser{d.buf}, nil
+}
+
+func (d *httpPutDestination) Read() (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	d.authenticate(req)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func (d *httpPutDestination) CurrentHash() ([]byte, error) {
+	r, err := d.Read()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+func (d *httpPutDestination) Commit() error {
+	req, err := http.NewRequest(d.method, d.url, bytes.NewReader(d.buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	d.authenticate(req)
+	for k, v := range d.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (d *httpPutDestination) Cleanup() {
+	d.buf = nil
+}
+
+func (d *httpPutDestination) authenticate(req *http.Request) {
+	if d.username != "" {
+		req.SetBasicAuth(d.username, d.password)
+	}
+}
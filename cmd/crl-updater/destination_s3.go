@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+// s3Destination publishes the CRL as an object in an S3-compatible bucket.
+type s3Destination struct {
+	bucket string
+	key    string
+	svc    *s3.S3
+
+	buf *bytes.Buffer
+}
+
+func newS3Destination(cfg *DestConfig) (*s3Destination, error) {
+	if cfg.Bucket == "" || cfg.Key == "" {
+		return nil, errors.New("s3 destination requires 'bucket' and 'key'")
+	}
+
+	awsCfg := aws.NewConfig()
+	if cfg.Region != "" {
+		awsCfg = awsCfg.WithRegion(cfg.Region)
+	}
+	if cfg.Endpoint != "" {
+		awsCfg = awsCfg.WithEndpoint(cfg.Endpoint).WithS3ForcePathStyle(true)
+	}
+	if cfg.AccessKey != "" || cfg.SecretKey != "" {
+		awsCfg = awsCfg.WithCredentials(credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""))
+	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3 session creation failed")
+	}
+
+	return &s3Destination{bucket: cfg.Bucket, key: cfg.Key, svc: s3.New(sess)}, nil
+}
+
+func (d *s3Destination) Open() (io.WriteCloser, error) {
+	d.buf = &bytes.Buffer{}
+	return bufferWriteCloser{d.buf}, nil
+}
+
+func (d *s3Destination) Read() (io.ReadCloser, error) {
+	out, err := d.svc.GetObject(&s3.GetObjectInput{Bucket: aws.String(d.bucket), Key: aws.String(d.key)})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (d *s3Destination) CurrentHash() ([]byte, error) {
+	r, err := d.Read()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+func (d *s3Destination) Commit() error {
+	_, err := d.svc.PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(d.bucket),
+		Key:           aws.String(d.key),
+		Body:          bytes.NewReader(d.buf.Bytes()),
+		ContentLength: aws.Int64(int64(d.buf.Len())),
+	})
+	return err
+}
+
+func (d *s3Destination) Cleanup() {
+	d.buf = nil
+}
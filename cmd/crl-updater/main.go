@@ -3,23 +3,20 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"crypto/sha256"
+	"encoding/hex"
 	"flag"
-	"fmt"
 	"hash"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
-	"os/user"
-	"path/filepath"
-	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/NeonSludge/crl-updater/pkg/utils"
-	"github.com/google/renameio"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -36,6 +33,9 @@ const (
 	DefaultSizeLimit       int64         = 10485760
 	DefaultSchedule        string        = "@hourly"
 	DefaultFileMode        uint32        = 0644
+	DefaultRetries         int           = 3
+	DefaultBackoff         time.Duration = time.Second
+	DefaultMaxBackoff      time.Duration = 30 * time.Second
 )
 
 type (
@@ -43,166 +43,254 @@ type (
 	Metrics struct {
 		Success      *prometheus.CounterVec
 		Error        *prometheus.CounterVec
+		Retries      *prometheus.CounterVec
+		NotModified  *prometheus.CounterVec
 		SuccessTotal prometheus.Counter
 		ErrorTotal   prometheus.Counter
+
+		ThisUpdate   *prometheus.GaugeVec
+		NextUpdate   *prometheus.GaugeVec
+		RevokedCount *prometheus.GaugeVec
+		IssuerInfo   *prometheus.GaugeVec
+		Expired      *prometheus.GaugeVec
+
+		DownloadDuration *prometheus.HistogramVec
+		CRLSize          *prometheus.HistogramVec
+		HashDuration     *prometheus.HistogramVec
+		HTTPResponses    *prometheus.CounterVec
 	}
 
 	// Main configuration
 	Config struct {
 		CRLJobs []*CRLJob `yaml:"jobs"`
+		// Use Prometheus native (exponential) histograms instead of fixed buckets
+		NativeHistograms bool `yaml:"native_histograms"`
 	}
 
 	// CRL update job definition
 	CRLJob struct {
 		ID cron.EntryID
 
-		// Source URL to download the CRL from
+		// Stable identifier for the admin API and the "job" metric label, unlike ID; defaults to config order
+		Name string `yaml:"name"`
+
+		// Source URL to download the CRL from: http(s):// or ldap(s)://
 		URL string `yaml:"url"`
-		// Destination file to save the CRL to
-		Destination string `yaml:"dest"`
-		// Desired file permissions for the CRL file
-		Mode uint32 `yaml:"mode"`
-		// Desired owner of the CRL file
-		Owner string `yaml:"owner"`
-		UID   int
-		// Desired group of the CRL file
-		Group string `yaml:"group"`
-		GID   int
+		// LDAP-specific source settings, used when URL's scheme is ldap or ldaps
+		LDAP LDAPConfig `yaml:"ldap"`
+		// Where to publish the downloaded CRL
+		Dest DestConfig `yaml:"dest"`
+		// Destination label for logs and metrics, derived from Dest
+		Destination string
 		// Force CRL file update, skip all checks
 		ForceUpdate bool `yaml:"force"`
-		// CRL update job cron schedule
+		// CRL update job cron schedule, or "auto" to follow the CRL's nextUpdate
 		Schedule string `yaml:"schedule"`
+		// How long before nextUpdate to refresh, in "auto" schedule mode (human readable)
+		RefreshBeforeHuman string `yaml:"refresh_before"`
+		RefreshBefore      time.Duration
 		// CRL file size limit
 		SizeLimit int64 `yaml:"limit"`
 		// CRL download attempt timeout
 		TimeoutHuman    string `yaml:"timeout"`
 		TimeoutDuration time.Duration
+		// Number of retries on a transient download failure; unset (nil)
+		// defaults to DefaultRetries, an explicit 0 disables retries
+		RetriesConfig *int `yaml:"retries"`
+		Retries       int
+		// Base delay between retries (human readable)
+		BackoffHuman string `yaml:"backoff"`
+		Backoff      time.Duration
+		// Maximum delay between retries (human readable)
+		MaxBackoffHuman string `yaml:"max_backoff"`
+		MaxBackoff      time.Duration
+		// Path to the sidecar file caching ETag/Last-Modified for conditional
+		// GETs; defaults to a name derived from Dest if unset.
+		MetaPath string `yaml:"meta_path"`
+
+		// The CRL source built from URL (and LDAP, if applicable) by Prepare
+		src Source
+		// The destination backend built from Dest by Prepare
+		dst Destination
 
 		// Global metrics to update from each job
 		Metrics *Metrics
+
+		// Serializes Run so the cron scheduler and an admin-triggered run
+		// can't execute the same job concurrently and race on dst's staged
+		// file state.
+		runMu sync.Mutex
+
+		// Guards the last-run status fields below
+		mu           sync.Mutex
+		lastRun      time.Time
+		lastError    string
+		lastHash     string
+		nextUpdate   time.Time
+		cacheExpires time.Time
+	}
+
+	// A point-in-time snapshot of a CRLJob's last run, for the admin API and metrics.
+	JobStatus struct {
+		ID          string    `json:"id"`
+		URL         string    `json:"url"`
+		Destination string    `json:"dest"`
+		Schedule    string    `json:"schedule"`
+		LastRun     time.Time `json:"last_run,omitempty"`
+		LastError   string    `json:"last_error,omitempty"`
+		LastHash    string    `json:"last_hash,omitempty"`
 	}
 )
 
-// Runs this CRL update job
-func (j *CRLJob) Run() {
-	// Create a temporary file for the CRL
-	tempFile, err := renameio.TempFile(renameio.TempDir(filepath.Dir(j.Destination)), j.Destination)
+// Status returns a snapshot of this job's configuration and last-run outcome.
+func (j *CRLJob) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return JobStatus{
+		ID:          j.Name,
+		URL:         j.URL,
+		Destination: j.Destination,
+		Schedule:    j.Schedule,
+		LastRun:     j.lastRun,
+		LastError:   j.lastError,
+		LastHash:    j.lastHash,
+	}
+}
+
+// Records the outcome of a run so the admin API and metrics can report it.
+func (j *CRLJob) setLastRun(err error, digest string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastRun = time.Now()
 	if err != nil {
-		log.Error().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("failed to create a temporary file")
-		j.Metrics.ErrorTotal.Inc()
-		j.Metrics.Error.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
+		j.lastError = err.Error()
 		return
 	}
-	defer tempFile.Cleanup()
+	j.lastError = ""
+	if digest != "" {
+		j.lastHash = digest
+	}
+}
 
-	tempHash := sha256.New()
+// Builds a logger with the process-wide format, writing to w.
+func newLogger(w io.Writer) zerolog.Logger {
+	return zerolog.New(w).With().Timestamp().Logger()
+}
 
-	// Download the CRL, compute its checksum
-	if err := downloadCRL(j.URL, tempFile, tempHash, j.TimeoutDuration, j.SizeLimit, j.ForceUpdate); err != nil {
-		log.Error().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("failed to download CRL")
-		j.Metrics.ErrorTotal.Inc()
-		j.Metrics.Error.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
-		return
+// Runs this CRL update job. Required by cron.Job.
+func (j *CRLJob) Run() {
+	j.runWithLogger(log.Logger)
+}
+
+// Runs the job like Run, also returning its top-level outcome log lines for the admin run-now endpoint.
+func (j *CRLJob) RunCaptured() (JobStatus, []string) {
+	var buf bytes.Buffer
+	logger := newLogger(zerolog.MultiLevelWriter(os.Stdout, &buf))
+
+	j.runWithLogger(logger)
+
+	if buf.Len() == 0 {
+		return j.Status(), nil
 	}
+	return j.Status(), strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
 
-	if !j.ForceUpdate {
-		// Open the destination file to check if the CRL has changed
-		destFile, err := os.Open(j.Destination)
-		if err == nil {
-			defer destFile.Close()
-
-			destHash := sha256.New()
-			if _, err := io.Copy(destHash, bufio.NewReader(destFile)); err != nil {
-				log.Error().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("failed to compare CRL files")
-				j.Metrics.ErrorTotal.Inc()
-				j.Metrics.Error.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
-				return
-			}
+// Serialized by runMu so a scheduled and an admin-triggered run can't race.
+func (j *CRLJob) runWithLogger(logger zerolog.Logger) {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
 
-			// No changes in the CRL, job is done
-			if bytes.Equal(tempHash.Sum(nil), destHash.Sum(nil)) {
-				log.Info().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Msg("CRL source did not change")
-				j.Metrics.SuccessTotal.Inc()
-				j.Metrics.Success.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
-				return
-			}
+	// Refresh CRL-derived metrics from whatever is currently published, even
+	// if this run's download fails, so staleness is always visible.
+	var cachedRaw []byte
+	defer func() { j.refreshCRLMetrics(cachedRaw) }()
 
-			// Close the destination file because Windows doesn't like replacing opened files
-			destFile.Close()
-		}
+	// Download the CRL into the destination backend, retrying transient failures
+	tempHash, notModified, raw, err := j.downloadWithRetry()
+	if err != nil {
+		logger.Error().Str("id", j.Name).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("failed to download CRL")
+		j.Metrics.ErrorTotal.Inc()
+		j.Metrics.Error.With(prometheus.Labels{"job": j.Name, "file": j.Destination}).Inc()
+		j.setLastRun(err, "")
+		return
+	}
+	if notModified {
+		// The destination wasn't touched this run; reuse the content
+		// downloadWithRetry already read instead of reading it again.
+		cachedRaw = raw
+		logger.Info().Str("id", j.Name).Str("dest", j.Destination).Str("url", j.URL).Msg("CRL source reports not modified")
+		j.Metrics.SuccessTotal.Inc()
+		j.Metrics.Success.With(prometheus.Labels{"job": j.Name, "file": j.Destination}).Inc()
+		j.setLastRun(nil, "")
+		return
 	}
+	defer j.dst.Cleanup()
 
-	if runtime.GOOS != "windows" {
-		if err := os.Chown(tempFile.Name(), j.UID, j.GID); err != nil {
-			log.Error().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("temporary file chown failed")
-			j.Metrics.ErrorTotal.Inc()
-			j.Metrics.Error.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
-			return
-		}
-		if err := os.Chmod(tempFile.Name(), os.FileMode(j.Mode)); err != nil {
-			log.Error().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("temporary file chmod failed")
-			j.Metrics.ErrorTotal.Inc()
-			j.Metrics.Error.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
+	if !j.ForceUpdate {
+		// Hash the currently published CRL to check if it has changed
+		if destHash, err := j.dst.CurrentHash(); err == nil && bytes.Equal(tempHash.Sum(nil), destHash) {
+			logger.Info().Str("id", j.Name).Str("dest", j.Destination).Str("url", j.URL).Msg("CRL source did not change")
+			j.Metrics.SuccessTotal.Inc()
+			j.Metrics.Success.With(prometheus.Labels{"job": j.Name, "file": j.Destination}).Inc()
+			j.setLastRun(nil, hex.EncodeToString(tempHash.Sum(nil)))
 			return
 		}
 	}
 
-	// Replace the destination file atomically
-	if err := tempFile.CloseAtomicallyReplace(); err != nil {
-		log.Error().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("failed to replace existing CRL file")
+	// Publish the downloaded CRL
+	if err := j.dst.Commit(); err != nil {
+		logger.Error().Str("id", j.Name).Str("dest", j.Destination).Str("url", j.URL).Err(err).Msg("failed to publish CRL")
 		j.Metrics.ErrorTotal.Inc()
-		j.Metrics.Error.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
+		j.Metrics.Error.With(prometheus.Labels{"job": j.Name, "file": j.Destination}).Inc()
+		j.setLastRun(err, "")
 		return
 	}
 
-	log.Info().Interface("id", j.ID).Str("dest", j.Destination).Str("url", j.URL).Msg("updated target CRL file")
+	logger.Info().Str("id", j.Name).Str("dest", j.Destination).Str("url", j.URL).Msg("updated target CRL file")
 	j.Metrics.SuccessTotal.Inc()
-	j.Metrics.Success.With(prometheus.Labels{"job": fmt.Sprintf("%v", j.ID), "file": j.Destination}).Inc()
+	j.Metrics.Success.With(prometheus.Labels{"job": j.Name, "file": j.Destination}).Inc()
+
+	// tempHash was never written to in force mode, so its digest is bogus
+	digest := ""
+	if !j.ForceUpdate {
+		digest = hex.EncodeToString(tempHash.Sum(nil))
+	}
+	j.setLastRun(nil, digest)
 }
 
 func (j *CRLJob) Prepare() error {
 	var err error
 
-	// Validate source and destination
-	if j.URL == "" || j.Destination == "" {
-		return errors.New("empty 'url' and/or 'dest' parameters")
+	// Validate source
+	if j.URL == "" {
+		return errors.New("empty 'url' parameter")
 	}
+	src, err := newSource(j)
+	if err != nil {
+		return errors.Wrap(err, "source configuration invalid")
+	}
+	j.src = src
 
-	// Validate owner and group on non-Windows hosts
-	if runtime.GOOS != "windows" {
-		if j.Owner != "" {
-			u, err := user.Lookup(j.Owner)
-			if err != nil {
-				return errors.Wrap(err, "user lookup failed")
-			}
-			j.UID, err = strconv.Atoi(u.Uid)
-			if err != nil {
-				return errors.Wrap(err, "uid conversion failed")
-			}
-		} else {
-			j.UID = os.Getuid()
-		}
-
-		if j.Group != "" {
-			g, err := user.LookupGroup(j.Group)
-			if err != nil {
-				return errors.Wrap(err, "group lookup failed")
-			}
-			j.GID, err = strconv.Atoi(g.Gid)
-			if err != nil {
-				return errors.Wrap(err, "gid conversion failed")
-			}
-		} else {
-			j.GID = os.Getgid()
-		}
-
-		if j.Mode == 0 {
-			j.Mode = DefaultFileMode
-		}
+	// Build and validate the destination backend
+	dst, err := newDestination(&j.Dest)
+	if err != nil {
+		return errors.Wrap(err, "destination configuration invalid")
+	}
+	j.dst = dst
+	j.Destination = j.Dest.Label()
+	if j.MetaPath == "" {
+		j.MetaPath = j.Dest.defaultMetaPath()
 	}
 
-	// Validate schedule (if not specified/invalid)
-	if _, err := cron.ParseStandard(j.Schedule); err != nil {
+	// Validate schedule (if not specified/invalid); "auto" follows the CRL's nextUpdate instead of cron
+	if strings.EqualFold(j.Schedule, "auto") {
+		j.Schedule = "auto"
+		if j.RefreshBefore, err = time.ParseDuration(j.RefreshBeforeHuman); err != nil {
+			j.RefreshBefore = DefaultRefreshBefore
+		}
+	} else if _, err := cron.ParseStandard(j.Schedule); err != nil {
 		j.Schedule = DefaultSchedule
 	}
 	// Validate download attempt timeout (if not specified/invalid)
@@ -213,51 +301,96 @@ func (j *CRLJob) Prepare() error {
 	if j.SizeLimit <= 0 {
 		j.SizeLimit = DefaultSizeLimit
 	}
+	// Validate retry policy: unset means "apply the default", an explicit
+	// 0 means "don't retry"
+	if j.RetriesConfig == nil {
+		j.Retries = DefaultRetries
+	} else {
+		j.Retries = *j.RetriesConfig
+	}
+	if j.Backoff, err = time.ParseDuration(j.BackoffHuman); err != nil {
+		j.Backoff = DefaultBackoff
+	}
+	if j.MaxBackoff, err = time.ParseDuration(j.MaxBackoffHuman); err != nil {
+		j.MaxBackoff = DefaultMaxBackoff
+	}
+	if j.MaxBackoff < j.Backoff {
+		j.MaxBackoff = j.Backoff
+	}
 
 	return nil
 }
 
-// Download CRL file and compute its checksum
-func downloadCRL(url string, w io.Writer, h hash.Hash, timeout time.Duration, limit int64, force bool) error {
-	c := &http.Client{Timeout: timeout, Transport: &http.Transport{DisableKeepAlives: true, DialContext: (&net.Dialer{KeepAlive: -1}).DialContext}}
-	r, err := c.Get(url)
-	if r != nil {
-		defer r.Body.Close()
-	}
+// Downloads the CRL from the job's source into w and computes its checksum in h, returning updated cache metadata.
+func (j *CRLJob) downloadCRL(w io.Writer, h hash.Hash, cache *cacheMeta) (*cacheMeta, error) {
+	labels := prometheus.Labels{"job": j.Name, "file": j.Destination}
+
+	start := time.Now()
+	body, newCache, err := j.src.Open(cache)
 	if err != nil {
-		return errors.Wrap(err, "http request failed")
+		return nil, err
 	}
+	defer body.Close()
 
 	// Destination is the temporary file writer
-	// Source is the entire response body
+	// Source is the entire fetched body, bounded by SizeLimit regardless of
+	// ForceUpdate so a hostile or oversized endpoint can't exhaust memory.
 	dest := w
-	src := io.Reader(r.Body)
+	src := utils.LimitStrictReader(body, j.SizeLimit)
 
-	if !force {
-		// Destination is the temporary file and its hash
-		dest = io.MultiWriter(w, h)
+	var hashElapsed time.Duration
+	if !j.ForceUpdate {
+		// Destination is the temporary file and its (timed) hash
+		dest = io.MultiWriter(w, &timedHash{Hash: h, elapsed: &hashElapsed})
 
-		// Read a small fragment of the response body first
+		// Read a small fragment of the body first
 		head := make([]byte, 24)
-		if _, err := io.ReadFull(r.Body, head); err != nil {
-			return errors.Wrap(err, "head read failed")
+		if _, err := io.ReadFull(src, head); err != nil {
+			if netErr, ok := err.(net.Error); ok {
+				return nil, &downloadError{cause: errors.Wrap(netErr, "head read failed"), retryable: true, reason: reasonNetwork}
+			}
+			return nil, errors.Wrap(err, "head read failed")
 		}
 
 		// Check if we're being offered a CRL file
 		if !isCRL(head) {
-			return errors.New("source is not a DER or PEM encoded CRL")
+			return nil, errors.New("source is not a DER or PEM encoded CRL")
 		}
 
-		// Source is the header and the remainder of the response body
-		src = utils.LimitStrictReader(io.MultiReader(bytes.NewReader(head), src), limit)
+		// Source is the header and the remainder of the body
+		src = io.MultiReader(bytes.NewReader(head), src)
 	}
 
 	// Copy source to destination and flush the temporary file writer
-	if _, err = io.Copy(dest, src); err != nil {
-		return errors.Wrap(err, "copy failed")
+	n, err := io.Copy(dest, src)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok {
+			return nil, &downloadError{cause: errors.Wrap(netErr, "copy failed"), retryable: true, reason: reasonNetwork}
+		}
+		return nil, errors.Wrap(err, "copy failed")
 	}
 
-	return nil
+	j.Metrics.DownloadDuration.With(labels).Observe(time.Since(start).Seconds())
+	j.Metrics.CRLSize.With(labels).Observe(float64(n))
+	if !j.ForceUpdate {
+		j.Metrics.HashDuration.With(labels).Observe(hashElapsed.Seconds())
+	}
+
+	return newCache, nil
+}
+
+// timedHash wraps a hash.Hash to measure time spent hashing, separately
+// from the time spent writing to the destination in the same io.Copy.
+type timedHash struct {
+	hash.Hash
+	elapsed *time.Duration
+}
+
+func (t *timedHash) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.Hash.Write(p)
+	*t.elapsed += time.Since(start)
+	return n, err
 }
 
 // Check if passed byte slice is a beginning of a DER or PEM encoded CRL
@@ -265,6 +398,19 @@ func isCRL(b []byte) bool {
 	return string(b) == X509CRLPEMHeader || (b[0] == 0x30 && (b[1] == 0x82 || b[1] == 0x83))
 }
 
+// Builds HistogramOpts with the given fixed buckets, or with a native
+// (exponential) bucket schema instead when native is true, trading bucket
+// configuration for tail resolution without label cardinality.
+func histogramOpts(name, help string, buckets []float64, native bool) prometheus.HistogramOpts {
+	opts := prometheus.HistogramOpts{Name: name, Help: help}
+	if native {
+		opts.NativeHistogramBucketFactor = 1.1
+	} else {
+		opts.Buckets = buckets
+	}
+	return opts
+}
+
 // Load and unmarshal YAML in the config file
 func makeConfig(r *bufio.Reader) (*Config, error) {
 	b, err := ioutil.ReadAll(r)
@@ -281,9 +427,49 @@ func makeConfig(r *bufio.Reader) (*Config, error) {
 	return cfg, nil
 }
 
+// Builds a cron scheduler from a config, preparing and registering each job.
+func buildScheduler(cfg *Config, metrics *Metrics) *cron.Cron {
+	sched := cron.New()
+	seenNames := make(map[string]bool)
+
+	for i, job := range cfg.CRLJobs {
+		if job.Name == "" {
+			job.Name = strconv.Itoa(i + 1)
+		}
+		if seenNames[job.Name] {
+			log.Error().Str("id", job.Name).Str("url", job.URL).Msg("skipping job: duplicate name")
+			continue
+		}
+		seenNames[job.Name] = true
+
+		// Prepare and validate job parameters
+		if err := job.Prepare(); err != nil {
+			log.Error().Str("id", job.Name).Str("url", job.URL).Err(err).Msg("skipping job")
+			continue
+		}
+
+		// Add job to scheduler
+		var id cron.EntryID
+		if job.Schedule == "auto" {
+			id = sched.Schedule(&autoSchedule{job: job, refreshBefore: job.RefreshBefore}, job)
+		} else {
+			var err error
+			if id, err = sched.AddJob(job.Schedule, job); err != nil {
+				log.Error().Str("dest", job.Destination).Str("url", job.URL).Err(err).Msg("failed to add CRL update job")
+				continue
+			}
+		}
+		job.ID = id
+		job.Metrics = metrics
+		log.Info().Str("id", job.Name).Str("dest", job.Destination).Str("url", job.URL).Msg("added CRL update job")
+	}
+
+	return sched
+}
+
 func main() {
 	zerolog.TimeFieldFormat = time.RFC3339
-	log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	log.Logger = newLogger(os.Stdout)
 
 	// cmd-line arguments
 	cfgPath := flag.String("cfg", "/etc/crl-updater.yaml", "path to a config file in YAML format")
@@ -312,6 +498,14 @@ func main() {
 			Name: "crl_updater_error",
 			Help: "Number of unsuccessful CRL update attempts per job.",
 		}, []string{"job", "file"}),
+		Retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crl_updater_retries_total",
+			Help: "Number of CRL download retries per job.",
+		}, []string{"job", "file", "reason"}),
+		NotModified: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crl_updater_not_modified_total",
+			Help: "Number of conditional CRL requests that came back 304 Not Modified.",
+		}, []string{"job", "file"}),
 		SuccessTotal: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "crl_updater_success_total",
 			Help: "Number of successful CRL update attempts.",
@@ -320,40 +514,81 @@ func main() {
 			Name: "crl_updater_error_total",
 			Help: "Number of unsuccessful CRL update attempts.",
 		}),
+		ThisUpdate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_updater_this_update_timestamp",
+			Help: "thisUpdate field of the published CRL, in Unix time.",
+		}, []string{"job", "file"}),
+		NextUpdate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_updater_next_update_timestamp",
+			Help: "nextUpdate field of the published CRL, in Unix time.",
+		}, []string{"job", "file"}),
+		RevokedCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_updater_revoked_count",
+			Help: "Number of revoked certificate entries in the published CRL.",
+		}, []string{"job", "file"}),
+		IssuerInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_updater_issuer_info",
+			Help: "Issuer of the published CRL, always 1.",
+		}, []string{"job", "file", "cn"}),
+		Expired: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_updater_expired",
+			Help: "1 if the published CRL is past its nextUpdate, 0 otherwise.",
+		}, []string{"job", "file"}),
+		DownloadDuration: prometheus.NewHistogramVec(histogramOpts(
+			"crl_updater_download_duration_seconds",
+			"Time spent downloading a CRL, from request to fully read response body.",
+			prometheus.ExponentialBuckets(0.05, 2, 12),
+			cfg.NativeHistograms,
+		), []string{"job", "file"}),
+		CRLSize: prometheus.NewHistogramVec(histogramOpts(
+			"crl_updater_crl_size_bytes",
+			"Size of downloaded CRL files.",
+			prometheus.ExponentialBuckets(1024, 4, 10),
+			cfg.NativeHistograms,
+		), []string{"job", "file"}),
+		HashDuration: prometheus.NewHistogramVec(histogramOpts(
+			"crl_updater_hash_duration_seconds",
+			"Time spent hashing a downloaded CRL.",
+			prometheus.ExponentialBuckets(0.001, 4, 10),
+			cfg.NativeHistograms,
+		), []string{"job", "file"}),
+		HTTPResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crl_updater_http_responses_total",
+			Help: "Number of HTTP responses received per job, by status code.",
+		}, []string{"job", "code"}),
 	}
 
 	pmReg := prometheus.NewRegistry()
 	pmReg.MustRegister(pmMetrics.Success)
 	pmReg.MustRegister(pmMetrics.Error)
+	pmReg.MustRegister(pmMetrics.Retries)
+	pmReg.MustRegister(pmMetrics.NotModified)
 	pmReg.MustRegister(pmMetrics.SuccessTotal)
 	pmReg.MustRegister(pmMetrics.ErrorTotal)
-
-	sched := cron.New()
-	jobs := cfg.CRLJobs
-
-	for _, job := range jobs {
-		// Prepare and validate job parameters
-		if err := job.Prepare(); err != nil {
-			log.Error().Str("dest", job.Destination).Str("url", job.URL).Err(err).Msg("skipping job")
-			continue
-		}
-
-		// Add job to scheduler
-		id, err := sched.AddJob(job.Schedule, job)
-		if err != nil {
-			log.Error().Str("dest", job.Destination).Str("url", job.URL).Err(err).Msg("failed to add CRL update job")
-			continue
-		}
-		job.ID = id
-		job.Metrics = pmMetrics
-		log.Info().Interface("id", job.ID).Str("dest", job.Destination).Str("url", job.URL).Msg("added CRL update job")
+	pmReg.MustRegister(pmMetrics.ThisUpdate)
+	pmReg.MustRegister(pmMetrics.NextUpdate)
+	pmReg.MustRegister(pmMetrics.RevokedCount)
+	pmReg.MustRegister(pmMetrics.IssuerInfo)
+	pmReg.MustRegister(pmMetrics.Expired)
+	pmReg.MustRegister(pmMetrics.DownloadDuration)
+	pmReg.MustRegister(pmMetrics.CRLSize)
+	pmReg.MustRegister(pmMetrics.HashDuration)
+	pmReg.MustRegister(pmMetrics.HTTPResponses)
+
+	app := &App{
+		ConfigPath: *cfgPath,
+		Config:     cfg,
+		Metrics:    pmMetrics,
+		Scheduler:  buildScheduler(cfg, pmMetrics),
 	}
+
 	// Run jobs
-	sched.Start()
+	app.Scheduler.Start()
 
-	// Serve metrics
-	http.Handle("/metrics", promhttp.HandlerFor(pmReg, promhttp.HandlerOpts{}))
-	if err := http.ListenAndServe(*metricsAddr, nil); err != nil {
+	// Serve metrics and the admin API on the same listener
+	mux := app.routes()
+	mux.Handle("/metrics", promhttp.HandlerFor(pmReg, promhttp.HandlerOpts{}))
+	if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
 		log.Fatal().Err(err).Msg("listen failed")
 	}
 }
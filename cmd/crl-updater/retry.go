@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"hash"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// Reasons a download attempt failed, used as the "reason" metric label.
+const (
+	reasonNetwork = "network"
+	reasonTimeout = "timeout"
+	reasonHTTP408 = "http_408"
+	reasonHTTP429 = "http_429"
+	reasonHTTP5xx = "http_5xx"
+)
+
+// downloadError carries enough context from a failed download attempt to
+// decide whether it's worth retrying.
+type downloadError struct {
+	cause      error
+	retryable  bool
+	reason     string
+	retryAfter time.Duration
+}
+
+func (e *downloadError) Error() string { return e.cause.Error() }
+
+// notModifiedError signals that a conditional GET came back 304: the
+// source hasn't changed since the cache metadata it carries was recorded.
+type notModifiedError struct {
+	meta *cacheMeta
+}
+
+func (e *notModifiedError) Error() string { return "source not modified" }
+
+// Downloads the CRL into the job's destination backend, retrying retryable
+// failures with exponential backoff and jitter. On success, the destination
+// is left open (staged, not yet committed) and its hash is returned. If a
+// conditional GET reports the source as unchanged, notModified is true and
+// the destination is left untouched.
+func (j *CRLJob) downloadWithRetry() (tempHash hash.Hash, notModified bool, cachedRaw []byte, err error) {
+	backoff := j.Backoff
+
+	var cache *cacheMeta
+	if !j.ForceUpdate {
+		cache = loadCacheMeta(j.MetaPath)
+		if cache != nil {
+			if raw, ok := j.readValidCRL(); ok {
+				cachedRaw = raw
+			} else {
+				// Destination is missing/corrupt; don't trust a 304 for it.
+				cache = nil
+			}
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		w, err := j.dst.Open()
+		if err != nil {
+			return nil, false, nil, errors.Wrap(err, "failed to open destination")
+		}
+
+		h := sha256.New()
+		newCache, err := j.downloadCRL(w, h, cache)
+		if err == nil {
+			j.saveCacheMeta(newCache)
+			return h, false, nil, nil
+		}
+		j.dst.Cleanup()
+
+		if nm, ok := err.(*notModifiedError); ok {
+			j.Metrics.NotModified.With(prometheus.Labels{"job": j.Name, "file": j.Destination}).Inc()
+			j.saveCacheMeta(nm.meta)
+			return nil, true, cachedRaw, nil
+		}
+
+		de, ok := err.(*downloadError)
+		if !ok || !de.retryable || attempt >= j.Retries {
+			return nil, false, nil, err
+		}
+
+		j.Metrics.Retries.With(prometheus.Labels{"job": j.Name, "file": j.Destination, "reason": de.reason}).Inc()
+
+		wait := de.retryAfter
+		if wait == 0 {
+			if backoff > j.MaxBackoff {
+				backoff = j.MaxBackoff
+			}
+			// Equal jitter: half the delay is fixed, half is random.
+			wait = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		}
+		log.Warn().Str("id", j.Name).Str("dest", j.Destination).Str("url", j.URL).Int("attempt", attempt+1).Dur("wait", wait).Err(err).Msg("retrying CRL download")
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > j.MaxBackoff {
+			backoff = j.MaxBackoff
+		}
+	}
+}
+
+// Parses a Retry-After header (either delay-seconds or an HTTP-date) into a duration.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
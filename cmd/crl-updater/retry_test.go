@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want time.Duration
+	}{
+		{"empty", "", 0},
+		{"delay seconds", "30", 30 * time.Second},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"http date in the future", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), time.Hour},
+		{"http date in the past", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+		{"garbage", "not-a-value", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseRetryAfter(c.in)
+			// Allow a small margin for the future-date case, which is
+			// computed relative to time.Now() on both sides.
+			diff := got - c.want
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > time.Second {
+				t.Errorf("parseRetryAfter(%q) = %v, want ~%v", c.in, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultRefreshBefore       time.Duration = time.Hour
+	DefaultAutoScheduleFloor   time.Duration = time.Minute
+	DefaultAutoScheduleCeiling time.Duration = 24 * time.Hour
+)
+
+// autoSchedule reschedules a job at its CRL's nextUpdate, minus a configured
+// lead time, instead of following a fixed cron spec. It implements
+// robfig/cron's Schedule interface.
+type autoSchedule struct {
+	job           *CRLJob
+	refreshBefore time.Duration
+}
+
+func (s *autoSchedule) Next(t time.Time) time.Time {
+	s.job.mu.Lock()
+	next := s.job.nextUpdate
+	expires := s.job.cacheExpires
+	s.job.mu.Unlock()
+
+	var delta time.Duration
+	if next.IsZero() {
+		// No CRL downloaded yet, try again soon.
+		delta = DefaultAutoScheduleFloor
+	} else {
+		// Don't bother checking before the CRL is due, nor before the
+		// source's own cache headers say it might change.
+		target := next.Add(-s.refreshBefore)
+		if expires.After(target) {
+			target = expires
+		}
+
+		delta = target.Sub(t)
+		if delta < DefaultAutoScheduleFloor {
+			delta = DefaultAutoScheduleFloor
+		}
+		if delta > DefaultAutoScheduleCeiling {
+			delta = DefaultAutoScheduleCeiling
+		}
+	}
+
+	// +/-10% jitter so jobs sharing a nextUpdate don't all refresh at once.
+	jitter := time.Duration(rand.Int63n(int64(delta)/5+1)) - delta/10
+
+	return t.Add(delta + jitter)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// withinJitter checks got against want's documented +/-10% jitter, with a
+// small absolute margin to absorb rounding at low durations.
+func withinJitter(t *testing.T, got, want time.Duration) {
+	t.Helper()
+	lo := want - want/10 - time.Second
+	hi := want + want/10 + time.Second
+	if got < lo || got > hi {
+		t.Errorf("delta = %v, want within [%v, %v] (~%v +/-10%%)", got, lo, hi, want)
+	}
+}
+
+func TestAutoScheduleNext(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("no CRL downloaded yet uses the floor", func(t *testing.T) {
+		job := &CRLJob{}
+		s := &autoSchedule{job: job, refreshBefore: time.Hour}
+		withinJitter(t, s.Next(now).Sub(now), DefaultAutoScheduleFloor)
+	})
+
+	t.Run("targets nextUpdate minus refreshBefore", func(t *testing.T) {
+		job := &CRLJob{}
+		job.nextUpdate = now.Add(2 * time.Hour)
+		s := &autoSchedule{job: job, refreshBefore: time.Hour}
+		withinJitter(t, s.Next(now).Sub(now), time.Hour)
+	})
+
+	t.Run("clamps to the floor when the target has already passed", func(t *testing.T) {
+		job := &CRLJob{}
+		job.nextUpdate = now.Add(time.Minute)
+		s := &autoSchedule{job: job, refreshBefore: time.Hour}
+		withinJitter(t, s.Next(now).Sub(now), DefaultAutoScheduleFloor)
+	})
+
+	t.Run("clamps to the ceiling for a far-future target", func(t *testing.T) {
+		job := &CRLJob{}
+		job.nextUpdate = now.Add(365 * 24 * time.Hour)
+		s := &autoSchedule{job: job, refreshBefore: 0}
+		withinJitter(t, s.Next(now).Sub(now), DefaultAutoScheduleCeiling)
+	})
+
+	t.Run("cacheExpires pushes the target later than nextUpdate minus refreshBefore", func(t *testing.T) {
+		job := &CRLJob{}
+		job.nextUpdate = now.Add(2 * time.Hour)
+		job.cacheExpires = now.Add(90 * time.Minute)
+		s := &autoSchedule{job: job, refreshBefore: time.Hour}
+		withinJitter(t, s.Next(now).Sub(now), 90*time.Minute)
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Source fetches a CRL's raw bytes from wherever it's published. Dispatched
+// by newSource on the job's URL scheme: HTTP(S) is the original download
+// behavior, LDAP(S) reads (or searches for) a directory attribute.
+type Source interface {
+	// Opens a reader over the current CRL bytes. cache carries the
+	// previous HTTP caching metadata; it's only meaningful to httpSource,
+	// which is also the only implementation that can return a
+	// *notModifiedError. Other sources should just ignore it and return a
+	// nil cacheMeta.
+	Open(cache *cacheMeta) (io.ReadCloser, *cacheMeta, error)
+}
+
+// Builds a Source for a job's CRL URL, dispatching on scheme.
+func newSource(j *CRLJob) (Source, error) {
+	u, err := url.Parse(j.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "CRL URL is invalid")
+	}
+
+	switch u.Scheme {
+	case "", "http", "https":
+		return newHTTPSource(j), nil
+	case "ldap", "ldaps":
+		return newLDAPSource(u, &j.LDAP)
+	default:
+		return nil, errors.Errorf("unsupported CRL source scheme: %q", u.Scheme)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// httpSource fetches the CRL over plain HTTP(S): the original download
+// behavior, now behind the Source interface. It's the only source that
+// supports conditional GET (If-None-Match / If-Modified-Since) and the
+// only one that can report a *notModifiedError.
+type httpSource struct {
+	job *CRLJob
+}
+
+func newHTTPSource(j *CRLJob) *httpSource {
+	return &httpSource{job: j}
+}
+
+func (s *httpSource) Open(cache *cacheMeta) (io.ReadCloser, *cacheMeta, error) {
+	j := s.job
+
+	c := &http.Client{Timeout: j.TimeoutDuration, Transport: &http.Transport{DisableKeepAlives: true, DialContext: (&net.Dialer{KeepAlive: -1}).DialContext}}
+
+	req, err := http.NewRequest(http.MethodGet, j.URL, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "request creation failed")
+	}
+	if !j.ForceUpdate {
+		applyConditionalHeaders(req, cache)
+	}
+
+	r, err := c.Do(req)
+	if err != nil {
+		reason := reasonNetwork
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			reason = reasonTimeout
+		}
+		return nil, nil, &downloadError{cause: errors.Wrap(err, "http request failed"), retryable: true, reason: reason}
+	}
+	j.Metrics.HTTPResponses.With(prometheus.Labels{"job": j.Name, "code": strconv.Itoa(r.StatusCode)}).Inc()
+
+	if r.StatusCode == http.StatusNotModified {
+		r.Body.Close()
+		return nil, nil, &notModifiedError{meta: responseCacheMeta(r, cache)}
+	}
+
+	if r.StatusCode != http.StatusOK {
+		r.Body.Close()
+		cause := errors.Errorf("unexpected HTTP status: %s", r.Status)
+
+		switch {
+		case r.StatusCode == http.StatusTooManyRequests:
+			return nil, nil, &downloadError{cause: cause, retryable: true, reason: reasonHTTP429, retryAfter: parseRetryAfter(r.Header.Get("Retry-After"))}
+		case r.StatusCode == http.StatusRequestTimeout:
+			return nil, nil, &downloadError{cause: cause, retryable: true, reason: reasonHTTP408, retryAfter: parseRetryAfter(r.Header.Get("Retry-After"))}
+		case r.StatusCode >= 500:
+			return nil, nil, &downloadError{cause: cause, retryable: true, reason: reasonHTTP5xx, retryAfter: parseRetryAfter(r.Header.Get("Retry-After"))}
+		default:
+			return nil, nil, cause
+		}
+	}
+
+	return r.Body, responseCacheMeta(r, cache), nil
+}
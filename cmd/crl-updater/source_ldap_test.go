@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestNewLDAPSource(t *testing.T) {
+	cases := []struct {
+		name          string
+		url           string
+		cfg           LDAPConfig
+		wantDialURL   string
+		wantBaseDN    string
+		wantScope     int
+		wantFilter    string
+		wantAttribute string
+	}{
+		{
+			name:          "bare DN defaults to a base-object read",
+			url:           "ldap://ldap.example.com/cn=crl1,dc=example,dc=com",
+			wantDialURL:   "ldap://ldap.example.com:389",
+			wantBaseDN:    "cn=crl1,dc=example,dc=com",
+			wantScope:     ldap.ScopeBaseObject,
+			wantFilter:    "(objectClass=*)",
+			wantAttribute: defaultLDAPAttribute,
+		},
+		{
+			name:          "ldaps defaults to port 636",
+			url:           "ldaps://ldap.example.com/cn=crl1,dc=example,dc=com",
+			wantDialURL:   "ldaps://ldap.example.com:636",
+			wantBaseDN:    "cn=crl1,dc=example,dc=com",
+			wantScope:     ldap.ScopeBaseObject,
+			wantFilter:    "(objectClass=*)",
+			wantAttribute: defaultLDAPAttribute,
+		},
+		{
+			name:          "explicit port is kept",
+			url:           "ldap://ldap.example.com:1389/dc=example,dc=com",
+			wantDialURL:   "ldap://ldap.example.com:1389",
+			wantBaseDN:    "dc=example,dc=com",
+			wantScope:     ldap.ScopeBaseObject,
+			wantFilter:    "(objectClass=*)",
+			wantAttribute: defaultLDAPAttribute,
+		},
+		{
+			name:          "RFC 4516 query segments: attributes?scope?filter",
+			url:           "ldap://ldap.example.com/dc=example,dc=com?certificateRevocationList?sub?(cn=ca1)",
+			wantDialURL:   "ldap://ldap.example.com:389",
+			wantBaseDN:    "dc=example,dc=com",
+			wantScope:     ldap.ScopeWholeSubtree,
+			wantFilter:    "(cn=ca1)",
+			wantAttribute: "certificateRevocationList",
+		},
+		{
+			name:          "one-level scope",
+			url:           "ldap://ldap.example.com/dc=example,dc=com??one",
+			wantDialURL:   "ldap://ldap.example.com:389",
+			wantBaseDN:    "dc=example,dc=com",
+			wantScope:     ldap.ScopeSingleLevel,
+			wantFilter:    "(objectClass=*)",
+			wantAttribute: defaultLDAPAttribute,
+		},
+		{
+			name:          "multiple requested attributes uses only the first",
+			url:           "ldap://ldap.example.com/dc=example,dc=com?certificateRevocationList,authorityRevocationList",
+			wantDialURL:   "ldap://ldap.example.com:389",
+			wantBaseDN:    "dc=example,dc=com",
+			wantScope:     ldap.ScopeBaseObject,
+			wantFilter:    "(objectClass=*)",
+			wantAttribute: "certificateRevocationList",
+		},
+		{
+			name:          "config attribute overrides the URL's",
+			url:           "ldap://ldap.example.com/dc=example,dc=com?certificateRevocationList",
+			cfg:           LDAPConfig{Attribute: "authorityRevocationList;binary"},
+			wantDialURL:   "ldap://ldap.example.com:389",
+			wantBaseDN:    "dc=example,dc=com",
+			wantScope:     ldap.ScopeBaseObject,
+			wantFilter:    "(objectClass=*)",
+			wantAttribute: "authorityRevocationList;binary",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u, err := url.Parse(c.url)
+			if err != nil {
+				t.Fatalf("url.Parse(%q): %v", c.url, err)
+			}
+
+			cfg := c.cfg
+			s, err := newLDAPSource(u, &cfg)
+			if err != nil {
+				t.Fatalf("newLDAPSource: %v", err)
+			}
+
+			if s.dialURL != c.wantDialURL {
+				t.Errorf("dialURL = %q, want %q", s.dialURL, c.wantDialURL)
+			}
+			if s.baseDN != c.wantBaseDN {
+				t.Errorf("baseDN = %q, want %q", s.baseDN, c.wantBaseDN)
+			}
+			if s.scope != c.wantScope {
+				t.Errorf("scope = %v, want %v", s.scope, c.wantScope)
+			}
+			if s.filter != c.wantFilter {
+				t.Errorf("filter = %q, want %q", s.filter, c.wantFilter)
+			}
+			if s.attribute != c.wantAttribute {
+				t.Errorf("attribute = %q, want %q", s.attribute, c.wantAttribute)
+			}
+		})
+	}
+}